@@ -8,8 +8,10 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	kedautil "github.com/kedacore/keda/v2/pkg/util"
+	"github.com/tidwall/gjson"
 	v2beta2 "k8s.io/api/autoscaling/v2beta2"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -17,15 +19,50 @@ import (
 	"k8s.io/metrics/pkg/apis/external_metrics"
 )
 
+const (
+	weatherAuthModeAPIKey = "apiKey"
+	weatherAuthModeBasic  = "basic"
+	weatherAuthModeBearer = "bearer"
+	weatherAuthModeTLS    = "tls"
+
+	weatherAPIKeyMethodHeader = "header"
+	weatherAPIKeyMethodQuery  = "query"
+)
+
 type weatherScaler struct {
 	metadata   *weatherMetadata
 	httpClient *http.Client
 }
 
 type weatherMetadata struct {
-	threshold  int64
-	host       string
-	preference string
+	threshold     float64
+	host          string
+	preference    string
+	valueLocation string
+	locations     map[string]string // label value -> host, for multi-location scalers
+
+	scalerIndex int
+
+	// apiKey auth
+	enableAPIKeyAuth bool
+	apiKey           string
+	keyParamName     string
+	method           string
+
+	// basic auth
+	enableBasicAuth bool
+	username        string
+	password        string
+
+	// bearer auth
+	enableBearerAuth bool
+	bearerToken      string
+
+	// TLS
+	enableTLS bool
+	cert      string
+	key       string
+	ca        string
 }
 
 type WeatherDataList struct {
@@ -47,6 +84,14 @@ func NewWeatherScaler(config *ScalerConfig) (Scaler, error) {
 		return nil, fmt.Errorf("error parsing weather metadata: %s", err)
 	}
 
+	if weatherMetadata.enableTLS {
+		tlsConfig, err := kedautil.NewTLSConfig(weatherMetadata.cert, weatherMetadata.key, weatherMetadata.ca)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
 	return &weatherScaler{
 		metadata:   weatherMetadata,
 		httpClient: httpClient,
@@ -58,62 +103,216 @@ func parseWeatherMetadata(config *ScalerConfig) (*weatherMetadata, error) {
 	meta := weatherMetadata{}
 
 	if val, ok := config.TriggerMetadata["threshold"]; ok && val != "" {
-		threshold, err := strconv.Atoi(val)
+		threshold, err := strconv.ParseFloat(val, 64)
 		if err != nil {
 			return nil, fmt.Errorf("threshold: error parsing threshold %s", err.Error())
-		} else {
-			meta.threshold = int64(threshold)
 		}
+		meta.threshold = threshold
 	}
 
-	if val, ok := config.TriggerMetadata["host"]; ok {
+	if val, ok := config.TriggerMetadata["locations"]; ok && val != "" {
+		locations, err := parseWeatherLocations(val)
+		if err != nil {
+			return nil, err
+		}
+		meta.locations = locations
+	} else if val, ok := config.TriggerMetadata["host"]; ok {
 		_, err := url.ParseRequestURI(val)
 		if err != nil {
 			return nil, fmt.Errorf("invalid URL: %s", err)
 		}
 		meta.host = val
 	} else {
-		return nil, fmt.Errorf("no host URI given")
+		return nil, fmt.Errorf("no host URI or locations given")
 	}
-	if config.TriggerMetadata["preference"] == "" {
-		return nil, fmt.Errorf("no preference given")
+	if val, ok := config.TriggerMetadata["valueLocation"]; ok && val != "" {
+		meta.valueLocation = val
+	} else if config.TriggerMetadata["preference"] == "" {
+		return nil, fmt.Errorf("no valueLocation or preference given")
 	}
 	meta.preference = config.TriggerMetadata["preference"]
 
+	if err := parseWeatherAuthenticationMetadata(config, &meta); err != nil {
+		return nil, err
+	}
+
+	meta.scalerIndex = config.ScalerIndex
+
 	return &meta, nil
 }
 
+// parseWeatherLocations parses a comma-separated list of label=host pairs,
+// e.g. "berlin=https://api.example.com/berlin,paris=https://api.example.com/paris".
+func parseWeatherLocations(val string) (map[string]string, error) {
+	locations := make(map[string]string)
+	for _, pair := range strings.Split(val, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid locations entry %q, expected label=host", pair)
+		}
+		if _, err := url.ParseRequestURI(parts[1]); err != nil {
+			return nil, fmt.Errorf("invalid URL for location %q: %s", parts[0], err)
+		}
+		locations[parts[0]] = parts[1]
+	}
+	return locations, nil
+}
+
+func parseWeatherAuthenticationMetadata(config *ScalerConfig, meta *weatherMetadata) error {
+	authMode, ok := config.TriggerMetadata["authMode"]
+	if !ok {
+		return nil
+	}
+
+	switch authMode {
+	case weatherAuthModeAPIKey:
+		if config.AuthParams["apiKey"] == "" {
+			return fmt.Errorf("no apiKey given")
+		}
+		meta.apiKey = config.AuthParams["apiKey"]
+		meta.enableAPIKeyAuth = true
+
+		meta.keyParamName = "apiKey"
+		if val, ok := config.TriggerMetadata["keyParamName"]; ok && val != "" {
+			meta.keyParamName = val
+		}
+
+		meta.method = weatherAPIKeyMethodHeader
+		if val, ok := config.TriggerMetadata["method"]; ok && val != "" {
+			if val != weatherAPIKeyMethodHeader && val != weatherAPIKeyMethodQuery {
+				return fmt.Errorf("method %q is not a supported authentication method", val)
+			}
+			meta.method = val
+		}
+	case weatherAuthModeBasic:
+		if config.AuthParams["username"] == "" {
+			return fmt.Errorf("no username given")
+		}
+		meta.username = config.AuthParams["username"]
+		meta.password = config.AuthParams["password"]
+		meta.enableBasicAuth = true
+	case weatherAuthModeBearer:
+		if config.AuthParams["bearerToken"] == "" {
+			return fmt.Errorf("no bearerToken given")
+		}
+		meta.bearerToken = config.AuthParams["bearerToken"]
+		meta.enableBearerAuth = true
+	case weatherAuthModeTLS:
+		if config.AuthParams["cert"] == "" {
+			return fmt.Errorf("no cert given")
+		}
+		if config.AuthParams["key"] == "" {
+			return fmt.Errorf("no key given")
+		}
+		meta.cert = config.AuthParams["cert"]
+		meta.key = config.AuthParams["key"]
+		meta.ca = config.AuthParams["ca"]
+		meta.enableTLS = true
+	default:
+		return fmt.Errorf("authMode %q is not a supported authentication type", authMode)
+	}
+
+	return nil
+}
+
 func (s *weatherScaler) IsActive(ctx context.Context) (bool, error) {
-	temperature, err := s.getWeather()
-	if err != nil {
-		return false, err
+	for _, host := range s.hosts() {
+		temperature, err := s.getWeather(ctx, host)
+		if err != nil {
+			return false, err
+		}
+		if temperature > s.metadata.threshold {
+			return true, nil
+		}
 	}
 
-	return (int64(temperature)) > s.metadata.threshold, nil
+	return false, nil
+}
+
+// hosts returns the set of endpoints to query: the configured locations, or
+// the single legacy host if no locations were given.
+func (s *weatherScaler) hosts() []string {
+	if len(s.metadata.locations) == 0 {
+		return []string{s.metadata.host}
+	}
+	hosts := make([]string, 0, len(s.metadata.locations))
+	for _, host := range s.metadata.locations {
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// weatherMetricName builds the base metric name from the value being
+// observed, without the per-trigger index prefix.
+func (s *weatherScaler) weatherMetricName(label string) string {
+	name := s.metadata.preference
+	if s.metadata.valueLocation != "" {
+		name = s.metadata.valueLocation
+	}
+	if label != "" {
+		return fmt.Sprintf("weather-%s-%s", label, name)
+	}
+	return fmt.Sprintf("weather-%s", name)
 }
 
 func (s *weatherScaler) GetMetricSpecForScaling() []v2beta2.MetricSpec {
-	targetMetricValue := resource.NewQuantity(int64(s.metadata.threshold), resource.DecimalSI)
-	externalMetric := &v2beta2.ExternalMetricSource{
-		Metric: v2beta2.MetricIdentifier{
-			Name: kedautil.NormalizeString(fmt.Sprintf("%s", "weather")),
-		},
-		Target: v2beta2.MetricTarget{
-			Type:         v2beta2.AverageValueMetricType,
-			AverageValue: targetMetricValue,
-		},
+	targetMetricValue := resource.NewMilliQuantity(int64(s.metadata.threshold*1000), resource.DecimalSI)
+
+	if len(s.metadata.locations) == 0 {
+		externalMetric := &v2beta2.ExternalMetricSource{
+			Metric: v2beta2.MetricIdentifier{
+				Name: kedautil.NormalizeString(fmt.Sprintf("s%d-%s", s.metadata.scalerIndex, s.weatherMetricName(""))),
+			},
+			Target: v2beta2.MetricTarget{
+				Type:         v2beta2.AverageValueMetricType,
+				AverageValue: targetMetricValue,
+			},
+		}
+		return []v2beta2.MetricSpec{{External: externalMetric, Type: externalMetricType}}
+	}
+
+	specs := make([]v2beta2.MetricSpec, 0, len(s.metadata.locations))
+	for label := range s.metadata.locations {
+		externalMetric := &v2beta2.ExternalMetricSource{
+			Metric: v2beta2.MetricIdentifier{
+				Name:     kedautil.NormalizeString(fmt.Sprintf("s%d-%s", s.metadata.scalerIndex, s.weatherMetricName(label))),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"location": label}},
+			},
+			Target: v2beta2.MetricTarget{
+				Type:         v2beta2.AverageValueMetricType,
+				AverageValue: targetMetricValue,
+			},
+		}
+		specs = append(specs, v2beta2.MetricSpec{External: externalMetric, Type: externalMetricType})
 	}
-	metricSpec := v2beta2.MetricSpec{External: externalMetric, Type: externalMetricType}
-	return []v2beta2.MetricSpec{metricSpec}
+	return specs
 }
 
 func (s *weatherScaler) GetMetrics(ctx context.Context, metricName string, metricSelector labels.Selector) ([]external_metrics.ExternalMetricValue, error) {
 
-	temp, _ := s.getWeather()
+	host := s.metadata.host
+	if len(s.metadata.locations) > 0 {
+		matched := false
+		for label, candidate := range s.metadata.locations {
+			if metricSelector.Matches(labels.Set{"location": label}) {
+				host = candidate
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no location matches metric selector %s", metricSelector.String())
+		}
+	}
+
+	temp, err := s.getWeather(ctx, host)
+	if err != nil {
+		return []external_metrics.ExternalMetricValue{}, fmt.Errorf("error getting weather: %s", err)
+	}
 
 	metric := external_metrics.ExternalMetricValue{
 		MetricName: metricName,
-		Value:      *resource.NewQuantity(int64(temp), resource.DecimalSI),
+		Value:      *resource.NewMilliQuantity(int64(temp*1000), resource.DecimalSI),
 		Timestamp:  metav1.Now(),
 	}
 
@@ -124,42 +323,73 @@ func (s *weatherScaler) Close() error {
 	return nil
 }
 
-func (s *weatherScaler) getJSONData(out interface{}) error {
+func (s *weatherScaler) getJSONData(ctx context.Context, host string) ([]byte, error) {
 
-	request, err := s.httpClient.Get(s.metadata.host)
+	req, err := http.NewRequestWithContext(ctx, "GET", host, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	body, err := ioutil.ReadAll(request.Body)
+	switch {
+	case s.metadata.enableAPIKeyAuth:
+		if s.metadata.method == weatherAPIKeyMethodQuery {
+			queryParams := req.URL.Query()
+			queryParams.Add(s.metadata.keyParamName, s.metadata.apiKey)
+			req.URL.RawQuery = queryParams.Encode()
+		} else {
+			req.Header.Add(s.metadata.keyParamName, s.metadata.apiKey)
+		}
+	case s.metadata.enableBasicAuth:
+		req.SetBasicAuth(s.metadata.username, s.metadata.password)
+	case s.metadata.enableBearerAuth:
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.metadata.bearerToken))
+	}
+
+	response, err := s.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = json.Unmarshal(body, &out)
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return body, nil
 }
 
-func (s *weatherScaler) getWeather() (int, error) {
+func (s *weatherScaler) getWeather(ctx context.Context, host string) (float64, error) {
+
+	body, err := s.getJSONData(ctx, host)
+	if err != nil {
+		return 100, err
+	}
 
-	var temp int
+	if s.metadata.valueLocation != "" {
+		r := gjson.GetBytes(body, s.metadata.valueLocation)
+		if !r.Exists() {
+			return 100, fmt.Errorf("valueLocation %s not found in response", s.metadata.valueLocation)
+		}
+		if r.Type != gjson.Number {
+			return 100, fmt.Errorf("value at valueLocation %s is not numeric", s.metadata.valueLocation)
+		}
+		return r.Num, nil
+	}
 
-	var wDat WeatherDataList
-	err := s.getJSONData(&wDat)
+	var temp float64
 
-	if err != nil {
+	var wDat WeatherDataList
+	if err := json.Unmarshal(body, &wDat); err != nil {
 		return 100, err
 	}
 
 	switch s.metadata.preference {
 	case "MinTemp":
-		temp = int(wDat.List[0].MinTemp)
+		temp = wDat.List[0].MinTemp
 	case "MaxTemp":
-		temp = int(wDat.List[0].MaxTemp)
+		temp = wDat.List[0].MaxTemp
 	case "TheTemp":
-		temp = int(wDat.List[0].TheTemp)
+		temp = wDat.List[0].TheTemp
 	}
 
 	return temp, nil